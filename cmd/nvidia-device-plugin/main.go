@@ -0,0 +1,174 @@
+// Command nvidia-device-plugin registers NVIDIA GPUs discovered via NVML
+// with kubelet as allocatable "nvidia.com/gpu" (or MIG) resources.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/fsnotify/fsnotify"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/sneakyfoot/nvidia-cdi-device-plugin/pkg/cdi"
+	"github.com/sneakyfoot/nvidia-cdi-device-plugin/pkg/dra"
+	"github.com/sneakyfoot/nvidia-cdi-device-plugin/pkg/mig"
+	"github.com/sneakyfoot/nvidia-cdi-device-plugin/pkg/nvml"
+	"github.com/sneakyfoot/nvidia-cdi-device-plugin/pkg/plugin"
+	"github.com/sneakyfoot/nvidia-cdi-device-plugin/pkg/sharing"
+	"github.com/sneakyfoot/nvidia-cdi-device-plugin/pkg/watcher"
+)
+
+// modeDevicePlugin and modeDRA select which kubelet integration main serves.
+const (
+	modeDevicePlugin = "device-plugin"
+	modeDRA          = "dra"
+)
+
+func main() {
+	mode := flag.String("mode", modeDevicePlugin,
+		"kubelet integration to serve: device-plugin or dra")
+	cdiMode := flag.String("cdi-mode", string(cdi.ModeRuntime),
+		"nvcdi discovery mode used to generate the CDI spec: management, nvml (runtime), or csv")
+	cdiSpecDir := flag.String("cdi-spec-dir", cdi.DefaultSpecDir, "directory to write the generated CDI spec to")
+	migStrategy := flag.String("mig-strategy", string(mig.StrategySingle),
+		"how MIG instances are advertised to kubelet: single or mixed")
+	sharingConfig := flag.String("sharing-config", "",
+		"path to a time-slicing YAML config (typically ConfigMap-mounted); unset disables sharing")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	generator := cdi.NewGenerator(cdi.Mode(*cdiMode), *cdiSpecDir)
+	if err := generator.Generate(); err != nil {
+		log.Fatalf("generating CDI spec: %v", err)
+	}
+
+	nvmlIface := nvml.New()
+	if err := nvmlIface.Init(); err != nil {
+		log.Fatalf("initializing NVML: %v", err)
+	}
+	defer nvmlIface.Shutdown()
+
+	devices, err := nvmlIface.Devices()
+	if err != nil {
+		log.Fatalf("enumerating GPUs: %v", err)
+	}
+
+	switch *mode {
+	case modeDevicePlugin:
+		runDevicePlugin(ctx, nvmlIface, devices, *migStrategy, *cdiSpecDir, *sharingConfig)
+	case modeDRA:
+		runDRA(ctx, devices, *cdiSpecDir)
+	default:
+		log.Fatalf("unknown --mode %q: must be %q or %q", *mode, modeDevicePlugin, modeDRA)
+	}
+}
+
+// runDevicePlugin serves the classic kubelet device-plugin API, one gRPC
+// server per MIG-strategy (and, if configured, time-sliced) resource name,
+// and re-emits ListAndWatch updates on every server when the CDI spec is
+// regenerated out of band.
+func runDevicePlugin(ctx context.Context, nvmlIface nvml.Interface, devices []nvml.Device, migStrategy, cdiSpecDir, sharingConfigPath string) {
+	resources, err := mig.Resources(mig.Strategy(migStrategy), devices)
+	if err != nil {
+		log.Fatalf("applying MIG strategy %q: %v", migStrategy, err)
+	}
+
+	sharingCfg, err := sharing.LoadConfig(sharingConfigPath)
+	if err != nil {
+		log.Fatalf("loading sharing config: %v", err)
+	}
+	sharedResources := sharing.Replicate(sharingCfg, resources)
+
+	if mpsResource := mpsResourceName(sharingCfg, sharedResources); mpsResource != "" {
+		daemon, err := sharing.StartControlDaemon(ctx, sharing.DefaultPipeDirectory, sharing.DefaultLogDirectory)
+		if err != nil {
+			log.Fatalf("starting nvidia-cuda-mps-control for %s: %v", mpsResource, err)
+		}
+		defer daemon.Stop()
+	}
+
+	servers := make([]*plugin.Server, len(sharedResources))
+	for i, r := range sharedResources {
+		var envVars map[string]string
+		if sharingCfg.MPSEnabled(r.Name) {
+			envVars = sharing.MPSEnvVars(sharing.DefaultPipeDirectory, sharing.DefaultLogDirectory)
+		}
+		servers[i] = plugin.NewServer(nvmlIface, r.Name, endpointFor(r.Name), r.Devices, envVars)
+	}
+
+	onCDISpecChange := func() {
+		for _, s := range servers {
+			s.TriggerUpdate()
+		}
+	}
+	if err := watcher.WatchFile(ctx, cdi.SpecPath(cdiSpecDir), fsnotify.Create|fsnotify.Write, onCDISpecChange); err != nil {
+		log.Fatalf("watching CDI spec directory: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for _, s := range servers {
+		wg.Add(1)
+		go func(s *plugin.Server) {
+			defer wg.Done()
+			if err := s.Run(ctx); err != nil {
+				log.Fatalf("device plugin for %s exited: %v", s.ResourceName(), err)
+			}
+		}(s)
+	}
+	wg.Wait()
+}
+
+// runDRA serves the Dynamic Resource Allocation kubelet plugin API. It
+// builds an in-cluster Kubernetes client to read back each ResourceClaim's
+// resolved allocation and to publish this node's ResourceSlice, since
+// kubelet's DRA requests carry only a claim's namespace/UID/name, not its
+// resolved devices.
+func runDRA(ctx context.Context, devices []nvml.Device, cdiSpecDir string) {
+	nodeName := os.Getenv("NODE_NAME")
+	if nodeName == "" {
+		log.Fatal("NODE_NAME must be set (e.g. via the downward API) to serve --mode=dra")
+	}
+
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		log.Fatalf("loading in-cluster Kubernetes config: %v", err)
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("constructing Kubernetes client: %v", err)
+	}
+
+	if err := dra.NewServer(devices, client, nodeName, cdiSpecDir).Run(ctx); err != nil {
+		log.Fatalf("DRA driver exited: %v", err)
+	}
+}
+
+// mpsResourceName returns the name of the first resource with CUDA MPS
+// enabled, or "" if none of resources has it configured. All MPS-enabled
+// resources share the one control daemon main starts, so only the first
+// match is needed to decide whether to start it.
+func mpsResourceName(cfg sharing.Config, resources []sharing.Resource) string {
+	for _, r := range resources {
+		if cfg.MPSEnabled(r.Name) {
+			return r.Name
+		}
+	}
+	return ""
+}
+
+// endpointFor derives the kubelet socket file name for a resource name,
+// e.g. "nvidia.com/mig-1g.5gb" becomes "nvidia-mig-1g.5gb.sock".
+func endpointFor(resourceName string) string {
+	name := strings.TrimPrefix(resourceName, "nvidia.com/")
+	return "nvidia-" + name + ".sock"
+}
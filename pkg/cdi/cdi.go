@@ -0,0 +1,182 @@
+// Package cdi generates the node's Container Device Interface (CDI) spec
+// using nvidia-container-toolkit's nvcdi generator, and resolves GPU UUIDs
+// to the CDI device names that generated spec advertises.
+package cdi
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/NVIDIA/nvidia-container-toolkit/pkg/nvcdi"
+	"github.com/NVIDIA/nvidia-container-toolkit/pkg/nvcdi/spec"
+	"tags.cncf.io/container-device-interface/pkg/cdi"
+)
+
+const (
+	// Vendor is the CDI vendor this plugin generates devices under.
+	Vendor = "nvidia.com"
+	// Class is the CDI device class this plugin generates devices under.
+	Class = "gpu"
+
+	// DefaultSpecDir is where the generated CDI spec is written, matching
+	// the default search path container runtimes use for CDI specs.
+	DefaultSpecDir = "/var/run/cdi"
+
+	specFileName = "nvidia.yaml"
+
+	claimSpecNamePrefix = "dra-claim-"
+)
+
+// Mode selects which nvcdi discovery mode populates the CDI spec.
+type Mode string
+
+const (
+	// ModeManagement generates a spec suitable for privileged management
+	// containers (e.g. nvidia-smi): it exposes the GPU device nodes but does
+	// not inject the CUDA driver libraries or runtime hook.
+	ModeManagement = Mode(nvcdi.ModeManagement)
+	// ModeRuntime generates a spec for ordinary workload containers: it
+	// mounts libcuda/libnvidia-ml and the nvidia-container-runtime-hook,
+	// matching the toolkit's NVML discovery mode.
+	ModeRuntime = Mode(nvcdi.ModeNvml)
+	// ModeCSV generates a spec from the toolkit's CSV mount-spec files, used
+	// on Tegra systems that have no NVML library.
+	ModeCSV = Mode(nvcdi.ModeCSV)
+)
+
+// Generator (re)builds the node's CDI spec on demand.
+type Generator struct {
+	mode    Mode
+	specDir string
+}
+
+// NewGenerator returns a Generator that writes its spec into specDir (or
+// DefaultSpecDir if empty) using the given discovery mode.
+func NewGenerator(mode Mode, specDir string) *Generator {
+	if specDir == "" {
+		specDir = DefaultSpecDir
+	}
+	return &Generator{mode: mode, specDir: specDir}
+}
+
+// Generate builds a CDI spec covering every GPU visible to the configured
+// discovery mode and writes it to <specDir>/nvidia.yaml, creating specDir if
+// necessary.
+func (g *Generator) Generate() error {
+	if err := os.MkdirAll(g.specDir, 0755); err != nil {
+		return fmt.Errorf("creating CDI spec directory %s: %w", g.specDir, err)
+	}
+
+	uuidNamer, err := nvcdi.NewDeviceNamer(nvcdi.DeviceNameStrategyUUID)
+	if err != nil {
+		return fmt.Errorf("constructing UUID device namer: %w", err)
+	}
+
+	lib, err := nvcdi.New(
+		nvcdi.WithMode(string(g.mode)),
+		nvcdi.WithDeviceNamers(uuidNamer),
+		nvcdi.WithVendor(Vendor),
+		nvcdi.WithClass(Class),
+	)
+	if err != nil {
+		return fmt.Errorf("constructing nvcdi library in mode %q: %w", g.mode, err)
+	}
+
+	spec, err := lib.GetSpec()
+	if err != nil {
+		return fmt.Errorf("generating CDI spec: %w", err)
+	}
+
+	if err := spec.Save(SpecPath(g.specDir)); err != nil {
+		return fmt.Errorf("writing CDI spec: %w", err)
+	}
+	return nil
+}
+
+// SpecPath returns the path Generate writes its spec to within specDir (or
+// DefaultSpecDir if empty), e.g. for callers that need to watch it for
+// changes.
+func SpecPath(specDir string) string {
+	if specDir == "" {
+		specDir = DefaultSpecDir
+	}
+	return filepath.Join(specDir, specFileName)
+}
+
+// DeviceName returns the fully qualified CDI device name for the GPU with
+// the given UUID, e.g. "nvidia.com/gpu=GPU-0c8398b4-f1e5-...". It matches
+// the UUID device-naming strategy Generate uses when building the spec.
+func DeviceName(uuid string) string {
+	return cdi.QualifiedName(Vendor, Class, uuid)
+}
+
+// WriteClaimSpec writes a CDI spec scoped to just the GPUs with the given
+// UUIDs to <specDir>/<ClaimSpecFileName(claimUID)>, creating specDir if
+// necessary, and returns the fully qualified CDI device name for each.
+//
+// Devices in this spec are named <uuid>-<claimUID> rather than plain uuid:
+// a claim-scoped spec sits alongside the node-level spec Generate produces
+// for the same physical GPUs, and CDI requires device names to be unique
+// across every spec advertising the same vendor/class.
+func WriteClaimSpec(specDir, claimUID string, uuids []string) ([]string, error) {
+	if specDir == "" {
+		specDir = DefaultSpecDir
+	}
+	if err := os.MkdirAll(specDir, 0755); err != nil {
+		return nil, fmt.Errorf("creating CDI spec directory %s: %w", specDir, err)
+	}
+
+	lib, err := nvcdi.New(
+		nvcdi.WithMode(nvcdi.ModeNvml),
+		nvcdi.WithVendor(Vendor),
+		nvcdi.WithClass(Class),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("constructing nvcdi library: %w", err)
+	}
+
+	deviceSpecs, err := lib.GetDeviceSpecsByID(uuids...)
+	if err != nil {
+		return nil, fmt.Errorf("generating CDI device specs for %v: %w", uuids, err)
+	}
+	edits, err := lib.GetCommonEdits()
+	if err != nil {
+		return nil, fmt.Errorf("generating common CDI edits: %w", err)
+	}
+
+	names := make([]string, len(deviceSpecs))
+	for i := range deviceSpecs {
+		deviceSpecs[i].Name = claimDeviceName(deviceSpecs[i].Name, claimUID)
+		names[i] = cdi.QualifiedName(Vendor, Class, deviceSpecs[i].Name)
+	}
+
+	claimSpec, err := spec.New(
+		spec.WithVendor(Vendor),
+		spec.WithClass(Class),
+		spec.WithDeviceSpecs(deviceSpecs),
+		spec.WithEdits(*edits.ContainerEdits),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("building claim CDI spec: %w", err)
+	}
+	if err := claimSpec.Save(ClaimSpecPath(specDir, claimUID)); err != nil {
+		return nil, fmt.Errorf("writing claim CDI spec: %w", err)
+	}
+	return names, nil
+}
+
+// ClaimSpecPath returns the path WriteClaimSpec writes claimUID's spec to
+// within specDir (or DefaultSpecDir if empty).
+func ClaimSpecPath(specDir, claimUID string) string {
+	if specDir == "" {
+		specDir = DefaultSpecDir
+	}
+	return filepath.Join(specDir, claimSpecNamePrefix+claimUID+".yaml")
+}
+
+// claimDeviceName scopes a device name to claimUID so it cannot collide
+// with the same physical GPU's entry in the node-level spec.
+func claimDeviceName(name, claimUID string) string {
+	return name + "-" + claimUID
+}
@@ -0,0 +1,27 @@
+package cdi
+
+import "testing"
+
+func TestDeviceName(t *testing.T) {
+	got := DeviceName("GPU-0c8398b4-f1e5-4e27-95b7-3e8f6e3a1111")
+	want := "nvidia.com/gpu=GPU-0c8398b4-f1e5-4e27-95b7-3e8f6e3a1111"
+	if got != want {
+		t.Errorf("DeviceName = %q, want %q", got, want)
+	}
+}
+
+func TestClaimDeviceName(t *testing.T) {
+	got := claimDeviceName("GPU-0c8398b4-f1e5-4e27-95b7-3e8f6e3a1111", "claim-1")
+	want := "GPU-0c8398b4-f1e5-4e27-95b7-3e8f6e3a1111-claim-1"
+	if got != want {
+		t.Errorf("claimDeviceName = %q, want %q", got, want)
+	}
+}
+
+func TestClaimSpecPath(t *testing.T) {
+	got := ClaimSpecPath("/var/run/cdi", "claim-1")
+	want := "/var/run/cdi/dra-claim-claim-1.yaml"
+	if got != want {
+		t.Errorf("ClaimSpecPath = %q, want %q", got, want)
+	}
+}
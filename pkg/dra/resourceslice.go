@@ -0,0 +1,52 @@
+package dra
+
+import (
+	"strings"
+
+	resourcev1 "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/sneakyfoot/nvidia-cdi-device-plugin/pkg/nvml"
+)
+
+// resourceSliceName returns the name of the ResourceSlice this server
+// publishes for nodeName. One driver publishes at most one ResourceSlice
+// per node per pool, so the node name makes a sufficiently unique name.
+func resourceSliceName(nodeName string) string {
+	return DriverName + "-" + nodeName
+}
+
+// deviceResourceName turns a GPU or MIG device UUID into a valid
+// resource.k8s.io Device name (a DNS label). MIG UUIDs contain "/", which a
+// DNS label cannot.
+func deviceResourceName(uuid string) string {
+	return strings.ToLower(strings.ReplaceAll(uuid, "/", "-"))
+}
+
+// buildResourceSlice describes devices as a single-ResourceSlice pool named
+// name, scoped to nodeName, so the scheduler can allocate ResourceClaims
+// against them.
+func buildResourceSlice(name, nodeName string, devices []nvml.Device) *resourcev1.ResourceSlice {
+	sliceDevices := make([]resourcev1.Device, len(devices))
+	for i, d := range devices {
+		sliceDevices[i] = resourcev1.Device{
+			Name: deviceResourceName(d.UUID),
+		}
+	}
+
+	return &resourcev1.ResourceSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: resourcev1.ResourceSliceSpec{
+			Driver: DriverName,
+			Pool: resourcev1.ResourcePool{
+				Name:               nodeName,
+				Generation:         1,
+				ResourceSliceCount: 1,
+			},
+			NodeName: &nodeName,
+			Devices:  sliceDevices,
+		},
+	}
+}
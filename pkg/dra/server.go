@@ -0,0 +1,306 @@
+// Package dra implements an alternative serving mode for this plugin: a
+// Kubernetes Dynamic Resource Allocation (DRA) kubelet plugin, registered
+// through kubelet's generic plugin watcher instead of the classic device
+// plugin registration flow used by pkg/plugin. It prepares CDI devices per
+// ResourceClaim rather than per container allocation request.
+//
+// The DRA kubelet plugin gRPC API has moved packages as the feature
+// progressed through Kubernetes releases; early proposals referenced
+// k8s.io/kubelet/pkg/apis/dra/v1alpha3, which no longer exists as of
+// kubelet v0.34. This package targets dra/v1.DRAPluginServer, the current
+// non-legacy package; dra/v1beta1 is kept around only as a conversion shim
+// for older kubelets and is not the one to build a new driver against.
+package dra
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"google.golang.org/grpc"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	draapi "k8s.io/kubelet/pkg/apis/dra/v1"
+	registerapi "k8s.io/kubelet/pkg/apis/pluginregistration/v1"
+
+	"github.com/sneakyfoot/nvidia-cdi-device-plugin/pkg/cdi"
+	"github.com/sneakyfoot/nvidia-cdi-device-plugin/pkg/nvml"
+)
+
+const (
+	// DriverName identifies this plugin to kubelet and scopes its plugin
+	// and registration socket paths. It also identifies the driver in the
+	// ResourceSlice this server publishes and in the Driver field of a
+	// resolved claim's allocation results.
+	DriverName = "gpu.nvidia.com"
+
+	// PluginsDir is kubelet's well-known directory for DRA plugin sockets,
+	// one subdirectory per driver.
+	PluginsDir = "/var/lib/kubelet/plugins"
+	// RegistrationDir is kubelet's well-known directory for registration
+	// sockets, watched by its generic plugin watcher.
+	RegistrationDir = "/var/lib/kubelet/plugins_registry"
+
+	pluginSocketName = "plugin.sock"
+	// supportedVersion is the DRA plugin API version this server speaks,
+	// advertised to kubelet during registration.
+	supportedVersion = "1.0.0"
+)
+
+// Server implements the kubelet v1beta1 DRAPluginServer. It publishes this
+// node's GPUs as a resource.k8s.io ResourceSlice, and when kubelet asks it
+// to prepare a claim, reads the claim's resolved allocation back from the
+// API server to find out which devices (how many, and, for MIG, which
+// profile) the scheduler actually assigned it, rather than guessing.
+//
+// Sharing strategies (pkg/sharing's time-sliced replica advertisement) are
+// not wired into DRA mode: ResourceSlice has its own, structurally
+// different sharing model (per-device AllowMultipleAllocations), and
+// mapping the classic plugin's replica-count config onto it is a separate
+// piece of work left for a follow-up change.
+type Server struct {
+	draapi.UnimplementedDRAPluginServer
+
+	pluginSocketPath string
+	regSocketPath    string
+
+	client     kubernetes.Interface
+	nodeName   string
+	cdiSpecDir string
+
+	devices       []nvml.Device
+	devicesByName map[string]nvml.Device // ResourceSlice device name -> device
+
+	mu       sync.Mutex
+	prepared map[string]preparedClaim // claim UID -> devices/CDI IDs allocated to it
+
+	pluginGRPC *grpc.Server
+	regGRPC    *grpc.Server
+}
+
+// preparedClaim is what NodePrepareResources resolved a claim to, cached so
+// a kubelet retry of the same claim UID doesn't re-fetch its allocation or
+// regenerate its CDI spec.
+type preparedClaim struct {
+	devices []nvml.Device
+	cdiIDs  []string
+}
+
+// NewServer returns a Server that advertises devices as nodeName's GPU pool
+// and resolves claims against it, using client to read back claims'
+// resolved allocations and publish the ResourceSlice. Per-claim CDI specs
+// are written to cdiSpecDir (or cdi.DefaultSpecDir if empty).
+func NewServer(devices []nvml.Device, client kubernetes.Interface, nodeName, cdiSpecDir string) *Server {
+	byName := make(map[string]nvml.Device, len(devices))
+	for _, d := range devices {
+		byName[deviceResourceName(d.UUID)] = d
+	}
+
+	return &Server{
+		pluginSocketPath: filepath.Join(PluginsDir, DriverName, pluginSocketName),
+		regSocketPath:    filepath.Join(RegistrationDir, DriverName+"-reg.sock"),
+		client:           client,
+		nodeName:         nodeName,
+		cdiSpecDir:       cdiSpecDir,
+		devices:          devices,
+		devicesByName:    byName,
+		prepared:         make(map[string]preparedClaim),
+	}
+}
+
+// Run publishes this node's ResourceSlice, starts the plugin and
+// registration gRPC servers, and blocks until ctx is canceled.
+func (s *Server) Run(ctx context.Context) error {
+	if err := s.publishResourceSlice(ctx); err != nil {
+		return fmt.Errorf("publishing ResourceSlice: %w", err)
+	}
+
+	pluginLis, err := listen(s.pluginSocketPath)
+	if err != nil {
+		return fmt.Errorf("listening on %s: %w", s.pluginSocketPath, err)
+	}
+	s.pluginGRPC = grpc.NewServer()
+	draapi.RegisterDRAPluginServer(s.pluginGRPC, s)
+	go func() {
+		if err := s.pluginGRPC.Serve(pluginLis); err != nil {
+			log.Printf("dra: plugin gRPC server exited: %v", err)
+		}
+	}()
+
+	regLis, err := listen(s.regSocketPath)
+	if err != nil {
+		s.pluginGRPC.Stop()
+		return fmt.Errorf("listening on %s: %w", s.regSocketPath, err)
+	}
+	s.regGRPC = grpc.NewServer()
+	registerapi.RegisterRegistrationServer(s.regGRPC, registrar{socketPath: s.pluginSocketPath})
+	go func() {
+		if err := s.regGRPC.Serve(regLis); err != nil {
+			log.Printf("dra: registration gRPC server exited: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	s.regGRPC.GracefulStop()
+	s.pluginGRPC.GracefulStop()
+	return nil
+}
+
+// listen removes any stale socket at path left behind by a previous run and
+// listens on it.
+func listen(path string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("creating socket directory for %s: %w", path, err)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("removing stale socket %s: %w", path, err)
+	}
+	return net.Listen("unix", path)
+}
+
+// NodePrepareResources generates a CDI spec for each requested claim out of
+// the devices the claim was actually resolved to, and returns the CDI
+// device IDs kubelet should pass to the container runtime.
+func (s *Server) NodePrepareResources(ctx context.Context, req *draapi.NodePrepareResourcesRequest) (*draapi.NodePrepareResourcesResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := &draapi.NodePrepareResourcesResponse{Claims: make(map[string]*draapi.NodePrepareResourceResponse, len(req.Claims))}
+
+	for _, claim := range req.Claims {
+		prepared, err := s.prepareLocked(ctx, claim)
+		if err != nil {
+			resp.Claims[claim.UID] = &draapi.NodePrepareResourceResponse{Error: err.Error()}
+			continue
+		}
+
+		result := make([]*draapi.Device, len(prepared.devices))
+		for i, d := range prepared.devices {
+			result[i] = &draapi.Device{
+				PoolName:     DriverName,
+				DeviceName:   d.UUID,
+				CDIDeviceIDs: []string{prepared.cdiIDs[i]},
+			}
+		}
+		resp.Claims[claim.UID] = &draapi.NodePrepareResourceResponse{Devices: result}
+	}
+
+	return resp, nil
+}
+
+// prepareLocked resolves claim's actual allocation and writes its CDI spec,
+// or returns the result already computed for it if NodePrepareResources has
+// already been called for this claim (kubelet may retry).
+func (s *Server) prepareLocked(ctx context.Context, claim *draapi.Claim) (preparedClaim, error) {
+	if prepared, ok := s.prepared[claim.UID]; ok {
+		return prepared, nil
+	}
+
+	resourceClaim, err := s.client.ResourceV1().ResourceClaims(claim.Namespace).Get(ctx, claim.Name, metav1.GetOptions{})
+	if err != nil {
+		return preparedClaim{}, fmt.Errorf("fetching ResourceClaim %s/%s: %w", claim.Namespace, claim.Name, err)
+	}
+	if resourceClaim.Status.Allocation == nil {
+		return preparedClaim{}, fmt.Errorf("ResourceClaim %s/%s has not been allocated yet", claim.Namespace, claim.Name)
+	}
+
+	var devices []nvml.Device
+	var uuids []string
+	for _, result := range resourceClaim.Status.Allocation.Devices.Results {
+		if result.Driver != DriverName {
+			continue
+		}
+		device, ok := s.devicesByName[result.Device]
+		if !ok {
+			return preparedClaim{}, fmt.Errorf("ResourceClaim %s/%s resolved to unknown device %q", claim.Namespace, claim.Name, result.Device)
+		}
+		devices = append(devices, device)
+		uuids = append(uuids, device.UUID)
+	}
+	if len(devices) == 0 {
+		return preparedClaim{}, fmt.Errorf("ResourceClaim %s/%s has no devices allocated to driver %s", claim.Namespace, claim.Name, DriverName)
+	}
+
+	cdiIDs, err := cdi.WriteClaimSpec(s.cdiSpecDir, claim.UID, uuids)
+	if err != nil {
+		return preparedClaim{}, fmt.Errorf("writing CDI spec for claim %s/%s: %w", claim.Namespace, claim.Name, err)
+	}
+
+	prepared := preparedClaim{devices: devices, cdiIDs: cdiIDs}
+	s.prepared[claim.UID] = prepared
+	return prepared, nil
+}
+
+// NodeUnprepareResources forgets each claim's resolved allocation and
+// removes its CDI spec.
+func (s *Server) NodeUnprepareResources(_ context.Context, req *draapi.NodeUnprepareResourcesRequest) (*draapi.NodeUnprepareResourcesResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := &draapi.NodeUnprepareResourcesResponse{Claims: make(map[string]*draapi.NodeUnprepareResourceResponse, len(req.Claims))}
+	for _, claim := range req.Claims {
+		if _, ok := s.prepared[claim.UID]; ok {
+			delete(s.prepared, claim.UID)
+			if err := os.Remove(cdi.ClaimSpecPath(s.cdiSpecDir, claim.UID)); err != nil && !os.IsNotExist(err) {
+				resp.Claims[claim.UID] = &draapi.NodeUnprepareResourceResponse{Error: fmt.Sprintf("removing CDI spec for claim %s: %v", claim.UID, err)}
+				continue
+			}
+		}
+		resp.Claims[claim.UID] = &draapi.NodeUnprepareResourceResponse{}
+	}
+	return resp, nil
+}
+
+// publishResourceSlice creates (or, on a restart, replaces) the
+// ResourceSlice advertising this node's GPUs under DriverName, the pool the
+// scheduler allocates claims from.
+func (s *Server) publishResourceSlice(ctx context.Context) error {
+	slice := buildResourceSlice(resourceSliceName(s.nodeName), s.nodeName, s.devices)
+
+	slices := s.client.ResourceV1().ResourceSlices()
+	if _, err := slices.Create(ctx, slice, metav1.CreateOptions{}); err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return fmt.Errorf("creating ResourceSlice %s: %w", slice.Name, err)
+		}
+
+		existing, err := slices.Get(ctx, slice.Name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("fetching existing ResourceSlice %s: %w", slice.Name, err)
+		}
+		slice.ResourceVersion = existing.ResourceVersion
+		if _, err := slices.Update(ctx, slice, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("updating ResourceSlice %s: %w", slice.Name, err)
+		}
+	}
+	return nil
+}
+
+// registrar implements registerapi.RegistrationServer, telling kubelet's
+// plugin watcher where to find the DRA plugin socket it just noticed under
+// RegistrationDir.
+type registrar struct {
+	registerapi.UnimplementedRegistrationServer
+	socketPath string
+}
+
+func (r registrar) GetInfo(context.Context, *registerapi.InfoRequest) (*registerapi.PluginInfo, error) {
+	return &registerapi.PluginInfo{
+		Type:              registerapi.DRAPlugin,
+		Name:              DriverName,
+		Endpoint:          r.socketPath,
+		SupportedVersions: []string{supportedVersion},
+	}, nil
+}
+
+func (r registrar) NotifyRegistrationStatus(_ context.Context, status *registerapi.RegistrationStatus) (*registerapi.RegistrationStatusResponse, error) {
+	if !status.PluginRegistered {
+		log.Printf("dra: kubelet rejected plugin registration: %s", status.Error)
+	}
+	return &registerapi.RegistrationStatusResponse{}, nil
+}
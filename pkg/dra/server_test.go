@@ -0,0 +1,104 @@
+package dra
+
+import (
+	"context"
+	"testing"
+
+	resourcev1 "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	draapi "k8s.io/kubelet/pkg/apis/dra/v1"
+
+	"github.com/sneakyfoot/nvidia-cdi-device-plugin/pkg/nvml"
+)
+
+// newAllocatedClaim returns a ResourceClaim already resolved by the
+// scheduler to device, as NodePrepareResources expects to find it.
+func newAllocatedClaim(namespace, name, uid, device string) *resourcev1.ResourceClaim {
+	return &resourcev1.ResourceClaim{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, UID: types.UID(uid)},
+		Status: resourcev1.ResourceClaimStatus{
+			Allocation: &resourcev1.AllocationResult{
+				Devices: resourcev1.DeviceAllocationResult{
+					Results: []resourcev1.DeviceRequestAllocationResult{
+						{Request: "gpu", Driver: DriverName, Pool: "test-node", Device: device},
+					},
+				},
+			},
+		},
+	}
+}
+
+// TestPrepareResourcesUnknownClaim covers the parts of prepareLocked that
+// run before cdi.WriteClaimSpec, which needs a real NVML library and so,
+// like cdi.Generate, is not exercised by this package's unit tests.
+func TestPrepareResourcesUnknownClaim(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset()
+
+	s := NewServer([]nvml.Device{{UUID: "GPU-0"}}, client, "test-node", t.TempDir())
+	claim := &draapi.Claim{Namespace: "default", UID: "claim-1", Name: "gpu-claim"}
+
+	resp, err := s.NodePrepareResources(ctx, &draapi.NodePrepareResourcesRequest{Claims: []*draapi.Claim{claim}})
+	if err != nil {
+		t.Fatalf("NodePrepareResources: %v", err)
+	}
+	if resp.Claims[claim.UID].Error == "" {
+		t.Fatal("expected an error for a claim the API server doesn't know about")
+	}
+}
+
+func TestPrepareResourcesUnallocatedClaim(t *testing.T) {
+	ctx := context.Background()
+	resourceClaim := &resourcev1.ResourceClaim{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "gpu-claim", UID: types.UID("claim-1")},
+	}
+	client := fake.NewSimpleClientset(resourceClaim)
+
+	s := NewServer([]nvml.Device{{UUID: "GPU-0"}}, client, "test-node", t.TempDir())
+	claim := &draapi.Claim{Namespace: "default", UID: "claim-1", Name: "gpu-claim"}
+
+	resp, err := s.NodePrepareResources(ctx, &draapi.NodePrepareResourcesRequest{Claims: []*draapi.Claim{claim}})
+	if err != nil {
+		t.Fatalf("NodePrepareResources: %v", err)
+	}
+	if resp.Claims[claim.UID].Error == "" {
+		t.Fatal("expected an error for a claim with no resolved allocation yet")
+	}
+}
+
+// TestPrepareResourcesUnknownDevice covers a claim resolved to a device
+// name this server doesn't recognize, another pre-CDI-generation error path.
+func TestPrepareResourcesUnknownDevice(t *testing.T) {
+	ctx := context.Background()
+	resourceClaim := newAllocatedClaim("default", "gpu-claim", "claim-1", "gpu-99")
+	client := fake.NewSimpleClientset(resourceClaim)
+
+	s := NewServer([]nvml.Device{{UUID: "GPU-0"}}, client, "test-node", t.TempDir())
+	claim := &draapi.Claim{Namespace: "default", UID: "claim-1", Name: "gpu-claim"}
+
+	resp, err := s.NodePrepareResources(ctx, &draapi.NodePrepareResourcesRequest{Claims: []*draapi.Claim{claim}})
+	if err != nil {
+		t.Fatalf("NodePrepareResources: %v", err)
+	}
+	if resp.Claims[claim.UID].Error == "" {
+		t.Fatal("expected an error for a claim resolved to an unknown device")
+	}
+}
+
+func TestNodeUnprepareResources(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset()
+
+	s := NewServer([]nvml.Device{{UUID: "GPU-0"}}, client, "test-node", t.TempDir())
+	claim := &draapi.Claim{Namespace: "default", UID: "claim-1", Name: "gpu-claim"}
+
+	resp, err := s.NodeUnprepareResources(ctx, &draapi.NodeUnprepareResourcesRequest{Claims: []*draapi.Claim{claim}})
+	if err != nil {
+		t.Fatalf("NodeUnprepareResources: %v", err)
+	}
+	if resp.Claims[claim.UID].Error != "" {
+		t.Fatalf("unexpected error unpreparing a claim that was never prepared: %+v", resp.Claims[claim.UID])
+	}
+}
@@ -0,0 +1,95 @@
+// Package mig groups NVML-discovered GPUs and MIG instances into the
+// kubelet resource names they should be advertised under, following the
+// "single" and "mixed" MIG strategies used across NVIDIA's Kubernetes GPU
+// stack.
+package mig
+
+import (
+	"fmt"
+
+	"github.com/sneakyfoot/nvidia-cdi-device-plugin/pkg/nvml"
+)
+
+// Strategy controls how MIG instances are mapped to kubelet resource names.
+type Strategy string
+
+const (
+	// StrategySingle requires every MIG-enabled GPU on the node to share the
+	// same MIG profile, and advertises every MIG instance (and every
+	// non-MIG GPU) under a single "nvidia.com/gpu" resource.
+	StrategySingle Strategy = "single"
+	// StrategyMixed advertises each MIG profile under its own resource
+	// name, e.g. "nvidia.com/mig-1g.5gb", while full GPUs remain under
+	// "nvidia.com/gpu".
+	StrategyMixed Strategy = "mixed"
+)
+
+// GPUResourceName is the resource name used for full (non-MIG) GPUs under
+// both strategies.
+const GPUResourceName = "nvidia.com/gpu"
+
+// Resource is one kubelet-visible resource name and the devices backing it.
+type Resource struct {
+	Name    string
+	Devices []nvml.Device
+}
+
+// Resources partitions devices into the Resources that strategy dictates
+// they be advertised under. It returns an error if strategy is "single" and
+// the node's MIG-enabled GPUs do not all share the same profile.
+func Resources(strategy Strategy, devices []nvml.Device) ([]Resource, error) {
+	switch strategy {
+	case StrategySingle:
+		return singleResources(devices)
+	case StrategyMixed:
+		return mixedResources(devices), nil
+	default:
+		return nil, fmt.Errorf("unknown MIG strategy %q", strategy)
+	}
+}
+
+// singleResources puts every device under GPUResourceName, after verifying
+// that all MIG instances share one profile, as the "single" strategy
+// requires.
+func singleResources(devices []nvml.Device) ([]Resource, error) {
+	var profile string
+	for _, d := range devices {
+		if !d.IsMIG {
+			continue
+		}
+		if profile == "" {
+			profile = d.MIGProfile
+			continue
+		}
+		if d.MIGProfile != profile {
+			return nil, fmt.Errorf("MIG strategy %q requires a uniform profile, found both %q and %q",
+				StrategySingle, profile, d.MIGProfile)
+		}
+	}
+
+	return []Resource{{Name: GPUResourceName, Devices: devices}}, nil
+}
+
+// mixedResources advertises each MIG profile under its own resource name
+// and groups remaining full GPUs under GPUResourceName.
+func mixedResources(devices []nvml.Device) []Resource {
+	order := []string{}
+	byName := make(map[string][]nvml.Device)
+
+	for _, d := range devices {
+		name := GPUResourceName
+		if d.IsMIG {
+			name = "nvidia.com/mig-" + d.MIGProfile
+		}
+		if _, ok := byName[name]; !ok {
+			order = append(order, name)
+		}
+		byName[name] = append(byName[name], d)
+	}
+
+	resources := make([]Resource, 0, len(order))
+	for _, name := range order {
+		resources = append(resources, Resource{Name: name, Devices: byName[name]})
+	}
+	return resources
+}
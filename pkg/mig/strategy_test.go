@@ -0,0 +1,61 @@
+package mig
+
+import (
+	"testing"
+
+	"github.com/sneakyfoot/nvidia-cdi-device-plugin/pkg/nvml"
+)
+
+func TestSingleResources(t *testing.T) {
+	devices := []nvml.Device{
+		{UUID: "MIG-0", IsMIG: true, MIGProfile: "1g.5gb"},
+		{UUID: "MIG-1", IsMIG: true, MIGProfile: "1g.5gb"},
+		{UUID: "GPU-0"},
+	}
+
+	resources, err := Resources(StrategySingle, devices)
+	if err != nil {
+		t.Fatalf("Resources: %v", err)
+	}
+	if len(resources) != 1 || resources[0].Name != GPUResourceName || len(resources[0].Devices) != 3 {
+		t.Fatalf("unexpected resources: %+v", resources)
+	}
+}
+
+func TestSingleResourcesRejectsMixedProfiles(t *testing.T) {
+	devices := []nvml.Device{
+		{UUID: "MIG-0", IsMIG: true, MIGProfile: "1g.5gb"},
+		{UUID: "MIG-1", IsMIG: true, MIGProfile: "3g.20gb"},
+	}
+
+	if _, err := Resources(StrategySingle, devices); err == nil {
+		t.Fatal("expected an error for non-uniform MIG profiles under the single strategy")
+	}
+}
+
+func TestMixedResources(t *testing.T) {
+	devices := []nvml.Device{
+		{UUID: "MIG-0", IsMIG: true, MIGProfile: "1g.5gb"},
+		{UUID: "MIG-1", IsMIG: true, MIGProfile: "3g.20gb"},
+		{UUID: "GPU-0"},
+	}
+
+	resources, err := Resources(StrategyMixed, devices)
+	if err != nil {
+		t.Fatalf("Resources: %v", err)
+	}
+
+	want := map[string]int{
+		"nvidia.com/gpu":         1,
+		"nvidia.com/mig-1g.5gb":  1,
+		"nvidia.com/mig-3g.20gb": 1,
+	}
+	if len(resources) != len(want) {
+		t.Fatalf("got %d resources, want %d: %+v", len(resources), len(want), resources)
+	}
+	for _, r := range resources {
+		if got := len(r.Devices); got != want[r.Name] {
+			t.Errorf("resource %s has %d devices, want %d", r.Name, got, want[r.Name])
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package nvml
+
+import (
+	"context"
+	"sync"
+)
+
+// fake is an in-memory Interface implementation for use in tests that
+// should run without a real GPU or the NVML shared library present.
+type fake struct {
+	mu      sync.Mutex
+	devices []Device
+
+	// subs mirrors the eventSets []gonvml.EventSet pattern in real.go: one
+	// entry per still-live Events call, so that Inject can fan an event out
+	// to every subscriber watching the matching device instead of only the
+	// most recent one.
+	subs []*fakeSubscriber
+}
+
+// fakeSubscriber is one outstanding Events call. inject is written by
+// Inject and read only by the goroutine Events started for this
+// subscriber, so Inject can never race that goroutine's close of the
+// channel it returns to its caller.
+type fakeSubscriber struct {
+	inject chan HealthEvent
+	ctx    context.Context
+	uuids  map[string]bool
+}
+
+// NewFake returns a fake Interface seeded with the given devices. Tests can
+// call Inject to simulate a health event arriving from NVML.
+func NewFake(devices ...Device) *fake {
+	return &fake{devices: devices}
+}
+
+func (f *fake) Init() error {
+	return nil
+}
+
+func (f *fake) Shutdown() error {
+	return nil
+}
+
+func (f *fake) Devices() ([]Device, error) {
+	return append([]Device(nil), f.devices...), nil
+}
+
+func (f *fake) Events(ctx context.Context, devices []Device) (<-chan HealthEvent, error) {
+	uuids := make(map[string]bool, len(devices))
+	for _, d := range devices {
+		uuids[d.UUID] = true
+	}
+
+	sub := &fakeSubscriber{
+		inject: make(chan HealthEvent),
+		ctx:    ctx,
+		uuids:  uuids,
+	}
+
+	f.mu.Lock()
+	f.subs = append(f.subs, sub)
+	f.mu.Unlock()
+
+	events := make(chan HealthEvent)
+	go func() {
+		defer close(events)
+		defer f.removeSubscriber(sub)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev := <-sub.inject:
+				select {
+				case events <- ev:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// removeSubscriber drops sub from f.subs once its Events goroutine has
+// exited, so Inject stops considering it and f.subs doesn't grow without
+// bound across a long-lived fake.
+func (f *fake) removeSubscriber(sub *fakeSubscriber) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, s := range f.subs {
+		if s == sub {
+			f.subs = append(f.subs[:i], f.subs[i+1:]...)
+			return
+		}
+	}
+}
+
+// Inject delivers ev to every still-live subscriber whose Events call
+// covers ev.UUID. It is a no-op if no such subscriber exists.
+func (f *fake) Inject(ev HealthEvent) {
+	f.mu.Lock()
+	subs := append([]*fakeSubscriber(nil), f.subs...)
+	f.mu.Unlock()
+
+	for _, sub := range subs {
+		if !sub.uuids[ev.UUID] {
+			continue
+		}
+		select {
+		case sub.inject <- ev:
+		case <-sub.ctx.Done():
+		}
+	}
+}
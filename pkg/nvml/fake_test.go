@@ -0,0 +1,75 @@
+package nvml
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestFakeEvents(t *testing.T) {
+	f := NewFake(Device{UUID: "GPU-0"})
+	devices, _ := f.Devices()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := f.Events(ctx, devices)
+	if err != nil {
+		t.Fatalf("Events: %v", err)
+	}
+
+	go f.Inject(HealthEvent{UUID: "GPU-0", Healthy: false, Reason: "test"})
+
+	select {
+	case ev := <-events:
+		if ev.UUID != "GPU-0" || ev.Healthy {
+			t.Errorf("unexpected event: %+v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for injected event")
+	}
+}
+
+// TestFakeEventsMultipleSubscribers exercises the same situation a mixed
+// MIG strategy creates in production: several plugin.Servers, each with its
+// own resource pool, calling Events concurrently against one shared
+// nvml.Interface. Inject must fan an event out to every subscriber whose
+// device set includes it, not just the most recently registered one.
+func TestFakeEventsMultipleSubscribers(t *testing.T) {
+	f := NewFake(Device{UUID: "GPU-0"}, Device{UUID: "GPU-1"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	gpu0Events, err := f.Events(ctx, []Device{{UUID: "GPU-0"}})
+	if err != nil {
+		t.Fatalf("Events(GPU-0): %v", err)
+	}
+	gpu1Events, err := f.Events(ctx, []Device{{UUID: "GPU-1"}})
+	if err != nil {
+		t.Fatalf("Events(GPU-1): %v", err)
+	}
+	bothEvents, err := f.Events(ctx, []Device{{UUID: "GPU-0"}, {UUID: "GPU-1"}})
+	if err != nil {
+		t.Fatalf("Events(GPU-0, GPU-1): %v", err)
+	}
+
+	f.Inject(HealthEvent{UUID: "GPU-0", Healthy: false, Reason: "xid"})
+
+	for name, ch := range map[string]<-chan HealthEvent{"gpu0Events": gpu0Events, "bothEvents": bothEvents} {
+		select {
+		case ev := <-ch:
+			if ev.UUID != "GPU-0" {
+				t.Errorf("%s: unexpected event: %+v", name, ev)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("%s: timed out waiting for GPU-0 event", name)
+		}
+	}
+
+	select {
+	case ev := <-gpu1Events:
+		t.Errorf("gpu1Events: unexpected event for a subscriber not watching GPU-0: %+v", ev)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,67 @@
+// Package nvml provides a narrow abstraction over NVIDIA's management
+// library (NVML) for enumerating physical GPUs and observing their health.
+// It is deliberately small: callers only get what the device plugin needs,
+// so a fake implementation can back tests that run without a GPU.
+package nvml
+
+import "context"
+
+// Device describes a single allocatable unit: either a full physical GPU,
+// or one MIG instance carved out of a MIG-enabled GPU.
+type Device struct {
+	// UUID uniquely identifies the device, e.g. "GPU-0c8398b4-f1e5-..." for
+	// a full GPU or "MIG-5c4bb1d7-..." for a MIG instance.
+	UUID string
+	// Index is the NVML enumeration index of the physical GPU this device
+	// belongs to (0-based). For MIG instances this is their parent GPU's
+	// index, not a per-instance index.
+	Index int
+	// MinorNumber is the parent GPU's /dev/nvidia<N> minor number.
+	MinorNumber int
+	// PCIBusID is the parent GPU's PCI bus ID, e.g. "00000000:41:00.0".
+	PCIBusID string
+	// ProductName is the marketing name NVML reports for the parent GPU,
+	// e.g. "NVIDIA A100-SXM4-40GB".
+	ProductName string
+	// IsMIG is true if this Device is a MIG instance rather than a full GPU.
+	IsMIG bool
+	// MIGProfile is the MIG profile name, e.g. "1g.5gb". Empty unless IsMIG.
+	MIGProfile string
+	// ParentUUID is the UUID of the MIG-enabled GPU this instance was
+	// carved out of. Empty unless IsMIG.
+	ParentUUID string
+}
+
+// HealthEvent reports a change in a device's health, detected from an NVML
+// event such as a critical XID error, a double-bit ECC error, or the GPU
+// falling off the PCI bus.
+type HealthEvent struct {
+	// UUID identifies the device the event pertains to.
+	UUID string
+	// Healthy is false when the device should be reported Unhealthy to
+	// kubelet.
+	Healthy bool
+	// Reason is a short, human-readable explanation suitable for logging.
+	Reason string
+}
+
+// Interface abstracts the subset of NVML the plugin needs. It is
+// implemented by the real library in real.go and by an in-memory fake in
+// fake.go so that tests can run on machines without a GPU.
+type Interface interface {
+	// Init initializes the underlying NVML library. It must be called
+	// before any other method, and paired with a call to Shutdown.
+	Init() error
+
+	// Shutdown releases all resources held by the underlying library.
+	Shutdown() error
+
+	// Devices returns every physical GPU visible to NVML, ordered by
+	// NVML index.
+	Devices() ([]Device, error)
+
+	// Events streams health events for the given devices until ctx is
+	// canceled or the underlying event loop hits an unrecoverable error.
+	// The returned channel is closed when the loop exits.
+	Events(ctx context.Context, devices []Device) (<-chan HealthEvent, error)
+}
@@ -0,0 +1,248 @@
+package nvml
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	nvlibdevice "github.com/NVIDIA/go-nvlib/pkg/nvlib/device"
+	gonvml "github.com/NVIDIA/go-nvml/pkg/nvml"
+)
+
+// applicationErrorXids are XID codes that indicate an error in the
+// application running on the GPU rather than a fault in the GPU itself.
+// They are not treated as health events.
+var applicationErrorXids = map[uint64]bool{
+	13: true, // Graphics Engine Exception
+	31: true, // GPU memory page fault
+	43: true, // GPU stopped processing
+	45: true, // Preemptive cleanup, due to previous errors
+	68: true, // Video processor exception
+}
+
+// gpuLostXid is the XID NVML reports when a GPU has fallen off the PCI bus
+// and is no longer reachable. It always marks the device unhealthy,
+// regardless of applicationErrorXids.
+const gpuLostXid = 79
+
+// real is the production Interface implementation, backed by the cgo
+// bindings in github.com/NVIDIA/go-nvml, with MIG enumeration delegated to
+// go-nvlib's higher-level device helpers.
+type real struct {
+	lib       gonvml.Interface
+	devicelib nvlibdevice.Interface
+
+	eventSetsMu sync.Mutex
+	eventSets   []gonvml.EventSet
+}
+
+// New returns the production NVML-backed Interface.
+func New() Interface {
+	lib := gonvml.New()
+	return &real{lib: lib, devicelib: nvlibdevice.New(lib)}
+}
+
+func (r *real) Init() error {
+	if ret := r.lib.Init(); ret != gonvml.SUCCESS {
+		return fmt.Errorf("nvml.Init: %v", ret)
+	}
+	return nil
+}
+
+func (r *real) Shutdown() error {
+	r.eventSetsMu.Lock()
+	sets := r.eventSets
+	r.eventSets = nil
+	r.eventSetsMu.Unlock()
+
+	for _, set := range sets {
+		set.Free()
+	}
+
+	if ret := r.lib.Shutdown(); ret != gonvml.SUCCESS {
+		return fmt.Errorf("nvml.Shutdown: %v", ret)
+	}
+	return nil
+}
+
+// Devices returns one Device per full GPU, or, for MIG-enabled GPUs, one
+// Device per MIG instance in place of the GPU itself.
+func (r *real) Devices() ([]Device, error) {
+	gpus, err := r.devicelib.GetDevices()
+	if err != nil {
+		return nil, fmt.Errorf("enumerating GPUs: %w", err)
+	}
+
+	var devices []Device
+	for i, gpu := range gpus {
+		uuid, ret := gpu.GetUUID()
+		if ret != gonvml.SUCCESS {
+			return nil, fmt.Errorf("Device.GetUUID(%d): %v", i, ret)
+		}
+
+		minor, ret := gpu.GetMinorNumber()
+		if ret != gonvml.SUCCESS {
+			return nil, fmt.Errorf("Device.GetMinorNumber(%d): %v", i, ret)
+		}
+
+		pci, ret := gpu.GetPciInfo()
+		if ret != gonvml.SUCCESS {
+			return nil, fmt.Errorf("Device.GetPciInfo(%d): %v", i, ret)
+		}
+
+		name, ret := gpu.GetName()
+		if ret != gonvml.SUCCESS {
+			return nil, fmt.Errorf("Device.GetName(%d): %v", i, ret)
+		}
+
+		migEnabled, err := gpu.IsMigEnabled()
+		if err != nil {
+			return nil, fmt.Errorf("checking MIG mode on GPU %d: %w", i, err)
+		}
+
+		if !migEnabled {
+			devices = append(devices, Device{
+				UUID:        uuid,
+				Index:       i,
+				MinorNumber: minor,
+				PCIBusID:    pciBusID(pci),
+				ProductName: name,
+			})
+			continue
+		}
+
+		migs, err := gpu.GetMigDevices()
+		if err != nil {
+			return nil, fmt.Errorf("listing MIG devices on GPU %d: %w", i, err)
+		}
+		for _, mig := range migs {
+			migUUID, ret := mig.GetUUID()
+			if ret != gonvml.SUCCESS {
+				return nil, fmt.Errorf("MigDevice.GetUUID on GPU %d: %v", i, ret)
+			}
+
+			profile, err := mig.GetProfile()
+			if err != nil {
+				return nil, fmt.Errorf("MigDevice.GetProfile on GPU %d: %w", i, err)
+			}
+
+			devices = append(devices, Device{
+				UUID:        migUUID,
+				Index:       i,
+				MinorNumber: minor,
+				PCIBusID:    pciBusID(pci),
+				ProductName: name,
+				IsMIG:       true,
+				MIGProfile:  profile.String(),
+				ParentUUID:  uuid,
+			})
+		}
+	}
+	return devices, nil
+}
+
+// pciBusID formats a gonvml.PciInfo the way NVML's command-line tools print
+// it, e.g. "00000000:41:00.0".
+func pciBusID(pci gonvml.PciInfo) string {
+	return fmt.Sprintf("%08X:%02X:%02X.0", pci.Domain, pci.Bus, pci.Device)
+}
+
+// Events registers for XID critical errors and double-bit ECC errors on the
+// physical GPU backing each of the given devices, then watches for them
+// until ctx is canceled. Because XID and ECC events are reported against
+// the physical GPU rather than individual MIG instances, an event affecting
+// a MIG-enabled GPU is reported once per MIG instance Device derived from
+// it.
+//
+// Events may be called concurrently (e.g. once per plugin.Server when a
+// mixed MIG strategy splits one physical GPU across several resource
+// pools): each call gets its own NVML event set, registered independently
+// against the same physical-GPU handle, which NVML supports. The set is
+// tracked on r so Shutdown frees every set a caller created, not just the
+// most recent one.
+func (r *real) Events(ctx context.Context, devices []Device) (<-chan HealthEvent, error) {
+	set, ret := r.lib.EventSetCreate()
+	if ret != gonvml.SUCCESS {
+		return nil, fmt.Errorf("nvml.EventSetCreate: %v", ret)
+	}
+	r.eventSetsMu.Lock()
+	r.eventSets = append(r.eventSets, set)
+	r.eventSetsMu.Unlock()
+
+	uuidsByIndex := make(map[int][]string)
+	for _, d := range devices {
+		uuidsByIndex[d.Index] = append(uuidsByIndex[d.Index], d.UUID)
+	}
+
+	eventTypes := uint64(gonvml.EventTypeXidCriticalError | gonvml.EventTypeDoubleBitEccError)
+	uuidsByHandle := make(map[gonvml.Device][]string, len(uuidsByIndex))
+	for index, uuids := range uuidsByIndex {
+		handle, ret := r.lib.DeviceGetHandleByIndex(index)
+		if ret != gonvml.SUCCESS {
+			return nil, fmt.Errorf("nvml.DeviceGetHandleByIndex(%d): %v", index, ret)
+		}
+		if ret := handle.RegisterEvents(eventTypes, set); ret != gonvml.SUCCESS {
+			return nil, fmt.Errorf("Device.RegisterEvents(index %d): %v", index, ret)
+		}
+		uuidsByHandle[handle] = uuids
+	}
+
+	events := make(chan HealthEvent)
+	go func() {
+		defer close(events)
+		for {
+			if ctx.Err() != nil {
+				return
+			}
+
+			data, ret := set.Wait(5000)
+			if ret == gonvml.ERROR_TIMEOUT {
+				continue
+			}
+			if ret != gonvml.SUCCESS {
+				return
+			}
+
+			uuids, known := uuidsByHandle[data.Device]
+			if !known {
+				continue
+			}
+
+			reason, unhealthy := classify(data)
+			if !unhealthy {
+				continue
+			}
+
+			for _, uuid := range uuids {
+				select {
+				case events <- HealthEvent{UUID: uuid, Healthy: false, Reason: reason}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// classify turns a raw NVML event into a health reason, reporting
+// unhealthy=false for events that should not affect device health (e.g.
+// application XIDs).
+func classify(data gonvml.EventData) (reason string, unhealthy bool) {
+	switch {
+	case data.EventType&gonvml.EventTypeXidCriticalError != 0:
+		xid := data.EventData
+		if xid == gpuLostXid {
+			return "GPU has fallen off the bus (Xid 79)", true
+		}
+		if applicationErrorXids[xid] {
+			return "", false
+		}
+		return fmt.Sprintf("critical Xid %d", xid), true
+
+	case data.EventType&gonvml.EventTypeDoubleBitEccError != 0:
+		return "double-bit ECC error", true
+	}
+	return "", false
+}
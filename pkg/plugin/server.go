@@ -0,0 +1,324 @@
+// Package plugin implements the kubelet device-plugin gRPC server that
+// advertises GPUs discovered via pkg/nvml.
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	"github.com/fsnotify/fsnotify"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/sneakyfoot/nvidia-cdi-device-plugin/pkg/cdi"
+	"github.com/sneakyfoot/nvidia-cdi-device-plugin/pkg/nvml"
+	"github.com/sneakyfoot/nvidia-cdi-device-plugin/pkg/watcher"
+)
+
+// connectionTimeout bounds how long the plugin waits to dial kubelet's
+// registration socket and how long kubelet is given to dial the plugin back.
+const connectionTimeout = 5 * time.Second
+
+// Device pairs a kubelet-visible device ID with the nvml.Device backing it.
+// In the common case ID equals the device's UUID; pkg/sharing assigns
+// distinct IDs like "GPU-0::0" and "GPU-0::1" to oversubscribed replicas of
+// one physical device under time-slicing, all of which still resolve back
+// to the same CDI device and health status.
+type Device struct {
+	ID string
+	nvml.Device
+}
+
+// Server implements the kubelet v1beta1 DevicePluginServer, backed by an
+// nvml.Interface for device discovery and health.
+type Server struct {
+	pluginapi.UnimplementedDevicePluginServer
+
+	resourceName string
+	socketPath   string
+	envVars      map[string]string
+
+	nvml nvml.Interface
+
+	mu      sync.Mutex
+	devices []Device
+	health  map[string]string
+
+	update chan struct{}
+	grpc   *grpc.Server
+}
+
+// NewServer returns a Server that advertises resourceName, e.g.
+// "nvidia.com/gpu", for the given devices, and serves on endpoint relative
+// to pluginapi.DevicePluginPath. envVars, if non-empty, is set on every
+// Allocate response for this resource, e.g. the CUDA_MPS_* variables
+// pkg/sharing adds when MPS is enabled.
+func NewServer(nvmlIface nvml.Interface, resourceName, endpoint string, devices []Device, envVars map[string]string) *Server {
+	health := make(map[string]string, len(devices))
+	for _, d := range devices {
+		health[d.UUID] = pluginapi.Healthy
+	}
+
+	return &Server{
+		resourceName: resourceName,
+		socketPath:   filepath.Join(pluginapi.DevicePluginPath, endpoint),
+		envVars:      envVars,
+		nvml:         nvmlIface,
+		devices:      devices,
+		health:       health,
+		update:       make(chan struct{}, 1),
+	}
+}
+
+// Devices converts plain nvml.Device values into Device, using each
+// device's UUID as its kubelet-visible ID. This is the identity mapping
+// used when time-slicing is not configured for a resource.
+func Devices(devices []nvml.Device) []Device {
+	out := make([]Device, len(devices))
+	for i, d := range devices {
+		out[i] = Device{ID: d.UUID, Device: d}
+	}
+	return out
+}
+
+// Run starts the gRPC server, registers with kubelet, and watches for
+// health events on this server's devices until ctx is canceled.
+func (s *Server) Run(ctx context.Context) error {
+	if len(s.devices) == 0 {
+		log.Printf("plugin(%s): no devices found", s.resourceName)
+	}
+
+	if err := s.serve(); err != nil {
+		return fmt.Errorf("starting gRPC server: %w", err)
+	}
+
+	if err := s.register(ctx); err != nil {
+		s.grpc.Stop()
+		return fmt.Errorf("registering with kubelet: %w", err)
+	}
+
+	// kubelet recreates its registration socket on restart; without
+	// re-registering here the plugin would silently stop receiving
+	// Allocate calls until its own pod was restarted too.
+	onKubeletRestart := func() {
+		if err := s.register(ctx); err != nil {
+			log.Printf("plugin(%s): re-registering with kubelet: %v", s.resourceName, err)
+		}
+	}
+	if err := watcher.WatchFile(ctx, pluginapi.KubeletSocket, fsnotify.Create, onKubeletRestart); err != nil {
+		s.grpc.Stop()
+		return fmt.Errorf("watching kubelet socket: %w", err)
+	}
+
+	events, err := s.nvml.Events(ctx, uniqueNVMLDevices(s.devices))
+	if err != nil {
+		s.grpc.Stop()
+		return fmt.Errorf("subscribing to NVML events: %w", err)
+	}
+	go s.watchHealth(ctx, events)
+
+	<-ctx.Done()
+	s.grpc.GracefulStop()
+	return nil
+}
+
+// uniqueNVMLDevices collapses devices down to one nvml.Device per UUID, so
+// that replicated devices sharing a physical GPU are only subscribed to
+// NVML health events once.
+func uniqueNVMLDevices(devices []Device) []nvml.Device {
+	seen := make(map[string]bool, len(devices))
+	out := make([]nvml.Device, 0, len(devices))
+	for _, d := range devices {
+		if seen[d.UUID] {
+			continue
+		}
+		seen[d.UUID] = true
+		out = append(out, d.Device)
+	}
+	return out
+}
+
+// serve starts listening for kubelet on s.socketPath.
+func (s *Server) serve() error {
+	if err := os.Remove(s.socketPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing stale socket %s: %w", s.socketPath, err)
+	}
+
+	lis, err := net.Listen("unix", s.socketPath)
+	if err != nil {
+		return fmt.Errorf("listening on %s: %w", s.socketPath, err)
+	}
+
+	s.grpc = grpc.NewServer()
+	pluginapi.RegisterDevicePluginServer(s.grpc, s)
+
+	go func() {
+		if err := s.grpc.Serve(lis); err != nil {
+			log.Printf("plugin(%s): gRPC server exited: %v", s.resourceName, err)
+		}
+	}()
+
+	return waitForSocket(s.socketPath, connectionTimeout)
+}
+
+// waitForSocket blocks until a unix socket at path accepts connections or
+// timeout elapses.
+func waitForSocket(path string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	conn, err := grpc.DialContext(ctx, "unix://"+path,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithBlock(),
+	)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// register announces the plugin's socket and resource name to kubelet.
+func (s *Server) register(ctx context.Context) error {
+	dialCtx, cancel := context.WithTimeout(ctx, connectionTimeout)
+	defer cancel()
+
+	conn, err := grpc.DialContext(dialCtx, "unix://"+pluginapi.KubeletSocket,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithBlock(),
+	)
+	if err != nil {
+		return fmt.Errorf("dialing kubelet at %s: %w", pluginapi.KubeletSocket, err)
+	}
+	defer conn.Close()
+
+	client := pluginapi.NewRegistrationClient(conn)
+	_, err = client.Register(ctx, &pluginapi.RegisterRequest{
+		Version:      pluginapi.Version,
+		Endpoint:     filepath.Base(s.socketPath),
+		ResourceName: s.resourceName,
+	})
+	return err
+}
+
+// watchHealth applies incoming NVML health events to s.health and wakes up
+// any active ListAndWatch stream.
+func (s *Server) watchHealth(ctx context.Context, events <-chan nvml.HealthEvent) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			status := pluginapi.Healthy
+			if !ev.Healthy {
+				status = pluginapi.Unhealthy
+				log.Printf("plugin(%s): device %s marked unhealthy: %s", s.resourceName, ev.UUID, ev.Reason)
+			}
+
+			s.mu.Lock()
+			s.health[ev.UUID] = status
+			s.mu.Unlock()
+
+			select {
+			case s.update <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// ResourceName returns the kubelet resource name this server advertises,
+// e.g. "nvidia.com/gpu".
+func (s *Server) ResourceName() string {
+	return s.resourceName
+}
+
+// TriggerUpdate causes ListAndWatch to re-send the current device list to
+// kubelet, e.g. after an operator regenerates the CDI spec out of band and
+// containers need to pick it up without a pod restart.
+func (s *Server) TriggerUpdate() {
+	select {
+	case s.update <- struct{}{}:
+	default:
+	}
+}
+
+// GetDevicePluginOptions returns the default (empty) options: the plugin
+// does not require PreStartContainer or preferred-allocation hints.
+func (s *Server) GetDevicePluginOptions(context.Context, *pluginapi.Empty) (*pluginapi.DevicePluginOptions, error) {
+	return &pluginapi.DevicePluginOptions{}, nil
+}
+
+// ListAndWatch streams the current device list to kubelet, and again
+// whenever a health event changes it.
+func (s *Server) ListAndWatch(_ *pluginapi.Empty, stream pluginapi.DevicePlugin_ListAndWatchServer) error {
+	if err := stream.Send(&pluginapi.ListAndWatchResponse{Devices: s.pluginDevices()}); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-s.update:
+			if err := stream.Send(&pluginapi.ListAndWatchResponse{Devices: s.pluginDevices()}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// pluginDevices renders the current device set as pluginapi.Device entries.
+func (s *Server) pluginDevices() []*pluginapi.Device {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]*pluginapi.Device, 0, len(s.devices))
+	for _, d := range s.devices {
+		out = append(out, &pluginapi.Device{
+			ID:     d.ID,
+			Health: s.health[d.UUID],
+		})
+	}
+	return out
+}
+
+// Allocate grants containers access to the requested GPUs by returning the
+// CDI device that pkg/cdi generated for the UUID backing each requested ID.
+// The container runtime resolves these against the spec written to
+// /var/run/cdi/nvidia.yaml. When this Server's resource has sharing envVars
+// configured (e.g. for CUDA MPS), they are set on every container response.
+func (s *Server) Allocate(_ context.Context, req *pluginapi.AllocateRequest) (*pluginapi.AllocateResponse, error) {
+	s.mu.Lock()
+	uuidByID := make(map[string]string, len(s.devices))
+	for _, d := range s.devices {
+		uuidByID[d.ID] = d.UUID
+	}
+	s.mu.Unlock()
+
+	resp := &pluginapi.AllocateResponse{}
+	for _, req := range req.ContainerRequests {
+		cresp := &pluginapi.ContainerAllocateResponse{Envs: s.envVars}
+		for _, id := range req.DevicesIds {
+			uuid, ok := uuidByID[id]
+			if !ok {
+				return nil, fmt.Errorf("unknown device id %q", id)
+			}
+			cresp.CdiDevices = append(cresp.CdiDevices, &pluginapi.CDIDevice{
+				Name: cdi.DeviceName(uuid),
+			})
+		}
+		resp.ContainerResponses = append(resp.ContainerResponses, cresp)
+	}
+	return resp, nil
+}
@@ -0,0 +1,51 @@
+package plugin
+
+import (
+	"testing"
+
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	"github.com/sneakyfoot/nvidia-cdi-device-plugin/pkg/nvml"
+)
+
+func TestAllocate(t *testing.T) {
+	fake := nvml.NewFake(nvml.Device{UUID: "GPU-0", MinorNumber: 3})
+	devices, err := fake.Devices()
+	if err != nil {
+		t.Fatalf("Devices: %v", err)
+	}
+
+	s := NewServer(fake, "nvidia.com/gpu", "test.sock", Devices(devices), nil)
+
+	resp, err := s.Allocate(nil, &pluginapi.AllocateRequest{
+		ContainerRequests: []*pluginapi.ContainerAllocateRequest{
+			{DevicesIds: []string{"GPU-0"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Allocate: %v", err)
+	}
+
+	if len(resp.ContainerResponses) != 1 || len(resp.ContainerResponses[0].CdiDevices) != 1 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	got := resp.ContainerResponses[0].CdiDevices[0].Name
+	if want := "nvidia.com/gpu=GPU-0"; got != want {
+		t.Errorf("CDI device name = %q, want %q", got, want)
+	}
+}
+
+func TestAllocateUnknownDevice(t *testing.T) {
+	fake := nvml.NewFake(nvml.Device{UUID: "GPU-0", MinorNumber: 0})
+	devices, _ := fake.Devices()
+	s := NewServer(fake, "nvidia.com/gpu", "test.sock", Devices(devices), nil)
+
+	_, err := s.Allocate(nil, &pluginapi.AllocateRequest{
+		ContainerRequests: []*pluginapi.ContainerAllocateRequest{
+			{DevicesIds: []string{"GPU-missing"}},
+		},
+	})
+	if err == nil {
+		t.Fatal("expected an error for an unknown device id")
+	}
+}
@@ -0,0 +1,82 @@
+// Package sharing implements NVIDIA-style time-slicing: it expands the
+// devices behind a kubelet resource into multiple oversubscribed replicas
+// per a ConfigMap-mounted YAML config, and optionally manages the CUDA
+// Multi-Process Service (MPS) control daemon so replicas can share a GPU
+// context instead of merely time-slicing it.
+package sharing
+
+import (
+	"fmt"
+	"os"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Config declares how many times each kubelet resource should be
+// advertised to kubelet, and whether replicas of it share a GPU context via
+// CUDA MPS. Resources with no entry here are left at their natural device
+// count.
+type Config struct {
+	Resources []ResourceReplicas `json:"resources"`
+}
+
+// ResourceReplicas configures time-slicing for one kubelet resource name,
+// e.g. "nvidia.com/gpu" or "nvidia.com/mig-1g.5gb".
+type ResourceReplicas struct {
+	// Name is the kubelet resource name, matching mig.Resource.Name.
+	Name string `json:"name"`
+	// Replicas is how many times each device of this resource is
+	// advertised to kubelet. Values below 2 leave the resource
+	// unreplicated.
+	Replicas int `json:"replicas"`
+	// MPS enables CUDA Multi-Process Service for this resource's
+	// replicas, so they share a single GPU context instead of merely
+	// time-slicing it.
+	MPS bool `json:"mps,omitempty"`
+}
+
+// LoadConfig reads a Config from a YAML file, typically mounted from a
+// ConfigMap. An empty path returns the zero Config, under which every
+// resource is left at its natural device count.
+func LoadConfig(path string) (Config, error) {
+	if path == "" {
+		return Config{}, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("reading sharing config %s: %w", path, err)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("parsing sharing config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// resource returns the configured ResourceReplicas for name, if any.
+func (c Config) resource(name string) (ResourceReplicas, bool) {
+	for _, r := range c.Resources {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	return ResourceReplicas{}, false
+}
+
+// Replicas returns how many times name's devices should be advertised,
+// defaulting to 1 (no sharing) when name is unconfigured.
+func (c Config) Replicas(name string) int {
+	if r, ok := c.resource(name); ok && r.Replicas > 1 {
+		return r.Replicas
+	}
+	return 1
+}
+
+// MPSEnabled reports whether replicas of name should share a GPU context
+// via CUDA MPS rather than plain time-slicing.
+func (c Config) MPSEnabled(name string) bool {
+	r, ok := c.resource(name)
+	return ok && r.MPS
+}
@@ -0,0 +1,53 @@
+package sharing
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfigEmptyPath(t *testing.T) {
+	cfg, err := LoadConfig("")
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if got := cfg.Replicas("nvidia.com/gpu"); got != 1 {
+		t.Errorf("Replicas = %d, want 1 for an empty config", got)
+	}
+	if cfg.MPSEnabled("nvidia.com/gpu") {
+		t.Error("MPSEnabled = true, want false for an empty config")
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sharing.yaml")
+	data := []byte(`
+resources:
+- name: nvidia.com/gpu
+  replicas: 4
+- name: nvidia.com/mig-1g.5gb
+  replicas: 2
+  mps: true
+`)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("writing config: %v", err)
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	if got := cfg.Replicas("nvidia.com/gpu"); got != 4 {
+		t.Errorf("Replicas(nvidia.com/gpu) = %d, want 4", got)
+	}
+	if got := cfg.Replicas("nvidia.com/unconfigured"); got != 1 {
+		t.Errorf("Replicas(nvidia.com/unconfigured) = %d, want 1", got)
+	}
+	if cfg.MPSEnabled("nvidia.com/gpu") {
+		t.Error("MPSEnabled(nvidia.com/gpu) = true, want false")
+	}
+	if !cfg.MPSEnabled("nvidia.com/mig-1g.5gb") {
+		t.Error("MPSEnabled(nvidia.com/mig-1g.5gb) = false, want true")
+	}
+}
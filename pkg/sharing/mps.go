@@ -0,0 +1,101 @@
+package sharing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+)
+
+const (
+	// DefaultPipeDirectory and DefaultLogDirectory match
+	// nvidia-cuda-mps-control's own defaults.
+	DefaultPipeDirectory = "/tmp/nvidia-mps"
+	DefaultLogDirectory  = "/tmp/nvidia-log"
+)
+
+// MPSEnvVars returns the environment variables a container must set to
+// have its CUDA context managed by the MPS control daemon listening on
+// pipeDir/logDir, for use in an Allocate response alongside the allocated
+// device's CDI device name.
+func MPSEnvVars(pipeDir, logDir string) map[string]string {
+	return map[string]string{
+		"CUDA_MPS_PIPE_DIRECTORY": pipeDir,
+		"CUDA_MPS_LOG_DIRECTORY":  logDir,
+	}
+}
+
+// ControlDaemon manages the lifecycle of an nvidia-cuda-mps-control
+// process, the sidecar daemon that must be running on a node before
+// containers using CUDA_MPS_PIPE_DIRECTORY can share a GPU context via MPS.
+//
+// nvidia-cuda-mps-control -d forks the actual control daemon into the
+// background and the launching process exits immediately, so the daemon
+// cannot be controlled through that process's stdin or waited on through
+// its *exec.Cmd. Every control command, including "quit", instead goes to
+// a fresh non-daemonized invocation that connects to the running daemon's
+// pipe, executes the command given on its stdin, and exits once it reads
+// EOF.
+type ControlDaemon struct {
+	pipeDir string
+	logDir  string
+}
+
+// StartControlDaemon launches nvidia-cuda-mps-control in daemon mode with
+// the given pipe and log directories and waits for the launcher to fork
+// and exit.
+func StartControlDaemon(ctx context.Context, pipeDir, logDir string) (*ControlDaemon, error) {
+	d := &ControlDaemon{pipeDir: pipeDir, logDir: logDir}
+
+	cmd := d.command(ctx, "-d")
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("starting nvidia-cuda-mps-control: %w", err)
+	}
+
+	return d, nil
+}
+
+// Stop asks the control daemon to exit via its "quit" command, sent
+// through a fresh control connection, and waits for that connection to
+// close.
+func (d *ControlDaemon) Stop() error {
+	return d.sendCommand(context.Background(), "quit")
+}
+
+// sendCommand runs a non-daemonized nvidia-cuda-mps-control that connects
+// to the running daemon's pipe, sends command followed by EOF, and waits
+// for the control connection to exit.
+func (d *ControlDaemon) sendCommand(ctx context.Context, command string) error {
+	cmd := d.command(ctx)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("opening nvidia-cuda-mps-control stdin: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("starting nvidia-cuda-mps-control: %w", err)
+	}
+
+	if _, err := io.WriteString(stdin, command+"\n"); err != nil {
+		stdin.Close()
+		return fmt.Errorf("sending %q to nvidia-cuda-mps-control: %w", command, err)
+	}
+	if err := stdin.Close(); err != nil {
+		return fmt.Errorf("closing nvidia-cuda-mps-control stdin: %w", err)
+	}
+
+	return cmd.Wait()
+}
+
+// command builds an nvidia-cuda-mps-control invocation scoped to this
+// daemon's pipe and log directories.
+func (d *ControlDaemon) command(ctx context.Context, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "nvidia-cuda-mps-control", args...)
+	cmd.Env = append(os.Environ(),
+		"CUDA_MPS_PIPE_DIRECTORY="+d.pipeDir,
+		"CUDA_MPS_LOG_DIRECTORY="+d.logDir,
+	)
+	return cmd
+}
@@ -0,0 +1,44 @@
+package sharing
+
+import (
+	"fmt"
+
+	"github.com/sneakyfoot/nvidia-cdi-device-plugin/pkg/mig"
+	"github.com/sneakyfoot/nvidia-cdi-device-plugin/pkg/plugin"
+)
+
+// Resource is a kubelet resource name and the plugin devices it should be
+// advertised as: one plugin.Device per replica when time-slicing is
+// configured for it, or one per physical/MIG device otherwise.
+type Resource struct {
+	Name    string
+	Devices []plugin.Device
+}
+
+// Replicate expands each of resources' devices per cfg, turning one
+// physical (or MIG) device into replica IDs like "GPU-0::0".."GPU-0::3"
+// when its resource name has Replicas: 4 configured. Every replica of a
+// device resolves back to that device's UUID, so it shares its CDI device
+// and health status with its siblings.
+func Replicate(cfg Config, resources []mig.Resource) []Resource {
+	out := make([]Resource, len(resources))
+	for i, r := range resources {
+		replicas := cfg.Replicas(r.Name)
+		if replicas < 2 {
+			out[i] = Resource{Name: r.Name, Devices: plugin.Devices(r.Devices)}
+			continue
+		}
+
+		devices := make([]plugin.Device, 0, len(r.Devices)*replicas)
+		for _, d := range r.Devices {
+			for n := 0; n < replicas; n++ {
+				devices = append(devices, plugin.Device{
+					ID:     fmt.Sprintf("%s::%d", d.UUID, n),
+					Device: d,
+				})
+			}
+		}
+		out[i] = Resource{Name: r.Name, Devices: devices}
+	}
+	return out
+}
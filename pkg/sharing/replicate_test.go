@@ -0,0 +1,54 @@
+package sharing
+
+import (
+	"testing"
+
+	"github.com/sneakyfoot/nvidia-cdi-device-plugin/pkg/mig"
+	"github.com/sneakyfoot/nvidia-cdi-device-plugin/pkg/nvml"
+)
+
+func TestReplicateUnconfiguredPassesThrough(t *testing.T) {
+	resources := []mig.Resource{
+		{Name: "nvidia.com/gpu", Devices: []nvml.Device{{UUID: "GPU-0"}}},
+	}
+
+	out := Replicate(Config{}, resources)
+	if len(out) != 1 || len(out[0].Devices) != 1 {
+		t.Fatalf("unexpected result: %+v", out)
+	}
+	if got := out[0].Devices[0].ID; got != "GPU-0" {
+		t.Errorf("ID = %q, want %q", got, "GPU-0")
+	}
+}
+
+func TestReplicateExpandsConfiguredResource(t *testing.T) {
+	resources := []mig.Resource{
+		{Name: "nvidia.com/gpu", Devices: []nvml.Device{{UUID: "GPU-0"}, {UUID: "GPU-1"}}},
+	}
+	cfg := Config{Resources: []ResourceReplicas{{Name: "nvidia.com/gpu", Replicas: 3}}}
+
+	out := Replicate(cfg, resources)
+	if len(out) != 1 || len(out[0].Devices) != 6 {
+		t.Fatalf("unexpected result: %+v", out)
+	}
+
+	want := map[string]bool{
+		"GPU-0::0": true, "GPU-0::1": true, "GPU-0::2": true,
+		"GPU-1::0": true, "GPU-1::1": true, "GPU-1::2": true,
+	}
+	for _, d := range out[0].Devices {
+		if !want[d.ID] {
+			t.Errorf("unexpected replica ID %q", d.ID)
+		}
+		delete(want, d.ID)
+	}
+	if len(want) != 0 {
+		t.Errorf("missing replica IDs: %v", want)
+	}
+
+	for _, d := range out[0].Devices {
+		if d.ID == "GPU-0::1" && d.UUID != "GPU-0" {
+			t.Errorf("replica %q maps to UUID %q, want %q", d.ID, d.UUID, "GPU-0")
+		}
+	}
+}
@@ -0,0 +1,56 @@
+// Package watcher notifies callers when a file on disk is created,
+// written, or removed, so that long-running servers can react to changes
+// made by other processes (kubelet restarting its registration socket, an
+// operator regenerating a CDI spec) without restarting themselves.
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// WatchFile watches path for fsnotify events matching ops and invokes
+// onChange for each one, until ctx is canceled. fsnotify cannot watch a
+// path that does not exist yet, so WatchFile watches path's parent
+// directory instead and filters events down to path itself; this also
+// lets callers catch path being removed and later recreated.
+func WatchFile(ctx context.Context, path string, ops fsnotify.Op, onChange func()) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("creating fsnotify watcher: %w", err)
+	}
+
+	dir := filepath.Dir(path)
+	if err := w.Add(dir); err != nil {
+		w.Close()
+		return fmt.Errorf("watching %s: %w", dir, err)
+	}
+
+	go func() {
+		defer w.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(ev.Name) == filepath.Clean(path) && ev.Op&ops != 0 {
+					onChange()
+				}
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("watcher: %s: %v", dir, err)
+			}
+		}
+	}()
+
+	return nil
+}
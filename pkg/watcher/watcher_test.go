@@ -0,0 +1,54 @@
+package watcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func TestWatchFileDetectsRecreation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "target")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var creates int32
+	if err := WatchFile(ctx, path, fsnotify.Create, func() {
+		atomic.AddInt32(&creates, 1)
+	}); err != nil {
+		t.Fatalf("WatchFile: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("v1"), 0644); err != nil {
+		t.Fatalf("creating %s: %v", path, err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for atomic.LoadInt32(&creates) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if atomic.LoadInt32(&creates) == 0 {
+		t.Fatal("onChange was not invoked after the file was created")
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("removing %s: %v", path, err)
+	}
+	if err := os.WriteFile(path, []byte("v2"), 0644); err != nil {
+		t.Fatalf("recreating %s: %v", path, err)
+	}
+
+	deadline = time.Now().Add(2 * time.Second)
+	for atomic.LoadInt32(&creates) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := atomic.LoadInt32(&creates); got < 2 {
+		t.Fatalf("onChange invoked %d times, want at least 2 after recreation", got)
+	}
+}